@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Activity is the canonical, source-agnostic representation of a single
+// workout. Every ActivityProvider maps its own wire format onto this shape
+// so the sheet-writing code never needs to know where an activity came from.
+type Activity struct {
+	ID                 string
+	Name               string
+	SportType          string
+	StartTime          time.Time
+	DistanceM          float64
+	MovingTime         time.Duration
+	ElapsedTime        time.Duration
+	TotalElevationGain float64
+	AverageSpeed       float64
+}
+
+// ActivityProvider fetches activities between since and until from some
+// source - Strava, a directory of exported GPX/TCX files, or (eventually)
+// something like Garmin Connect or Runkeeper.
+type ActivityProvider interface {
+	Fetch(ctx context.Context, since, until time.Time) ([]Activity, error)
+}
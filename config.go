@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/sheets/v4"
+	"gopkg.in/yaml.v2"
+)
+
+// lastSeenSafetyDelta is subtracted from "now" before persisting a task's
+// LastSeenTimestamp, so an activity Strava/a watch uploads a little late
+// isn't missed on the next run.
+const lastSeenSafetyDelta = 10 * time.Minute
+
+// SyncTask describes one athlete's sync: where to fetch activities from and
+// which spreadsheet to write them to.
+type SyncTask struct {
+	Name               string    `yaml:"name"`
+	Source             string    `yaml:"source"` // "strava" (default) or "gpx"
+	SourcePath         string    `yaml:"source_path,omitempty"`
+	StravaClientID     string    `yaml:"strava_client_id,omitempty"`
+	StravaClientSecret string    `yaml:"strava_client_secret,omitempty"`
+	StravaTokenFile    string    `yaml:"strava_token_file,omitempty"`
+	SpreadsheetID      string    `yaml:"spreadsheet_id"`
+	Timezone           string    `yaml:"timezone"`
+	LastSeenTimestamp  time.Time `yaml:"last_seen_timestamp"`
+}
+
+// Config is the top-level config.yaml shape: a list of independent sync
+// tasks, so one cron invocation can sync multiple athletes into their own
+// spreadsheets.
+type Config struct {
+	SheetsCredentialsFile string     `yaml:"sheets_credentials_file"`
+	Tasks                 []SyncTask `yaml:"tasks"`
+}
+
+// loadConfig reads and parses a config.yaml.
+func loadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read config file %q: %w", path, err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("couldn't parse config file %q: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// saveConfig writes cfg back to path. Called after each task syncs
+// successfully so its LastSeenTimestamp is persisted.
+func saveConfig(path string, cfg *Config) error {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal config: %w", err)
+	}
+	return os.WriteFile(path, b, 0600)
+}
+
+// runAuthorizeStrava runs the interactive Strava OAuth flow for the named
+// task and caches the resulting token, so a task can be bootstrapped once by
+// hand before it's ever synced unattended.
+func runAuthorizeStrava(cfg *Config, taskName string) error {
+	for i := range cfg.Tasks {
+		task := &cfg.Tasks[i]
+		if task.Name != taskName {
+			continue
+		}
+		conf, err := stravaConfig(task.StravaClientID, task.StravaClientSecret)
+		if err != nil {
+			return err
+		}
+		tokenFile := task.StravaTokenFile
+		if tokenFile == "" {
+			tokenFile = defaultStravaTokenFile
+		}
+		return authorizeStrava(conf, tokenFile)
+	}
+	return fmt.Errorf("no task named %q in config", taskName)
+}
+
+// runSyncTasks processes every task in cfg in order, syncing each athlete's
+// activities into their own spreadsheet tabs and persisting cfg back to
+// configPath after each task that succeeds with commit set and dashboardOnly
+// unset, including a task that found no new activities. sinceOverride,
+// if non-empty, is a YYYY-MM-DD date that forces every task to sync from
+// that date instead of its own LastSeenTimestamp, for a manual backfill.
+func runSyncTasks(ctx context.Context, srv *sheets.Service, cfg *Config, configPath string, commit, dashboardOnly bool, sinceOverride string) {
+	for i := range cfg.Tasks {
+		task := &cfg.Tasks[i]
+		taskLog := log.WithField("task", task.Name)
+
+		lastSeen, err := runSyncTask(ctx, srv, task, commit, dashboardOnly, sinceOverride)
+		if err != nil {
+			taskLog.Errorf("sync failed: %v", err)
+			continue
+		}
+		taskLog.Info("sync succeeded")
+
+		if !commit || dashboardOnly || lastSeen.IsZero() {
+			continue
+		}
+		task.LastSeenTimestamp = lastSeen
+		if err := saveConfig(configPath, cfg); err != nil {
+			taskLog.Errorf("couldn't persist last_seen_timestamp: %v", err)
+		}
+	}
+}
+
+// runSyncTask runs a single task: fetch activities since its last sync (or
+// since sinceOverride, if set, for a manual backfill), upsert them into the
+// task's spreadsheet, and refresh its dashboard. It returns the
+// LastSeenTimestamp the caller should persist on success.
+func runSyncTask(ctx context.Context, srv *sheets.Service, task *SyncTask, commit, dashboardOnly bool, sinceOverride string) (time.Time, error) {
+	loc, err := time.LoadLocation(task.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone %q: %w", task.Timezone, err)
+	}
+
+	if dashboardOnly {
+		if commit {
+			if _, err := refreshDashboard(srv, task.SpreadsheetID, loc); err != nil {
+				return time.Time{}, fmt.Errorf("couldn't refresh dashboard: %w", err)
+			}
+		}
+		return time.Time{}, nil
+	}
+
+	var provider ActivityProvider
+	switch task.Source {
+	case "", "strava":
+		provider, err = newStravaProvider(ctx, task.StravaClientID, task.StravaClientSecret, task.StravaTokenFile)
+		if err != nil {
+			return time.Time{}, err
+		}
+	case "gpx":
+		if task.SourcePath == "" {
+			return time.Time{}, fmt.Errorf("source_path is required when source is gpx")
+		}
+		provider = newGPXProvider(task.SourcePath)
+	default:
+		return time.Time{}, fmt.Errorf("unknown source %q", task.Source)
+	}
+
+	since := task.LastSeenTimestamp
+	if sinceOverride != "" {
+		since, err = time.ParseInLocation("2006-01-02", sinceOverride, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("couldn't parse -since %q: %w", sinceOverride, err)
+		}
+	} else if since.IsZero() {
+		since = time.Now().In(loc).AddDate(0, 0, -1)
+	}
+	until := time.Now().In(loc)
+
+	activities, err := provider.Fetch(ctx, since, until)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("couldn't fetch activities: %w", err)
+	}
+	if len(activities) == 0 {
+		log.WithField("task", task.Name).Info("no new activities")
+		return until.Add(-lastSeenSafetyDelta), nil
+	}
+
+	if !commit {
+		log.WithField("task", task.Name).Infof("would upsert %d activities (dry run)", len(activities))
+		return until.Add(-lastSeenSafetyDelta), nil
+	}
+
+	mostRecentRow, err := upsertActivities(srv, task.SpreadsheetID, activities)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("couldn't upsert activities: %w", err)
+	}
+
+	const dateColumn = 1 // column B, per the RowMapper column layout
+	for tab, row := range mostRecentRow {
+		sheetID, err := sheetIDForTab(srv, task.SpreadsheetID, tab)
+		if err != nil {
+			log.WithField("task", task.Name).Warnf("couldn't color-code recency for %s: %v", tab, err)
+			continue
+		}
+		if err := colorCodeRecency(srv, task.SpreadsheetID, sheetID, row-1, dateColumn); err != nil {
+			log.WithField("task", task.Name).Warnf("couldn't color-code recency for %s: %v", tab, err)
+		}
+	}
+
+	if _, err := refreshDashboard(srv, task.SpreadsheetID, loc); err != nil {
+		log.WithField("task", task.Name).Warnf("couldn't refresh dashboard: %v", err)
+	}
+
+	return until.Add(-lastSeenSafetyDelta), nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/sheets/v4"
+)
+
+// dashboardRange is where refreshDashboard writes its rolling totals.
+const dashboardRange = "dashboard!A1:B4"
+
+// sheetsEpoch is day zero of Google Sheets' date serial number system, used
+// to decode the Date column when read back with ValueRenderOption
+// "UNFORMATTED_VALUE".
+var sheetsEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// mileageTabs are the sport tabs whose Date/distance columns feed the
+// dashboard (swims record meters, which readSheetMileage converts to miles).
+// weight_training has no distance column, so it's left out.
+var mileageTabs = []string{"runs", "rides", "swims"}
+
+// dashboardStats is the set of rolling numbers shown on the dashboard tab.
+type dashboardStats struct {
+	Mileage7Day   float64
+	Mileage30Day  float64
+	Mileage365Day float64
+	StreakDays    int
+}
+
+// dateMileage is a (date, miles) pair read back from a sport tab, used to
+// compute dashboardStats without caring which sport it came from.
+type dateMileage struct {
+	Date  time.Time
+	Miles float64
+}
+
+// parseSheetsDate converts a Date-column cell value read with
+// ValueRenderOption "UNFORMATTED_VALUE" back into a time.Time. Dates come
+// back as the number of days since sheetsEpoch rather than the ISO string
+// upsertActivities wrote - reading with the default FORMATTED_VALUE instead
+// would return the spreadsheet locale's rendering (e.g. "7/26/2026" instead
+// of "2026-07-26"), which silently fails to parse.
+func parseSheetsDate(v interface{}, loc *time.Location) (time.Time, bool) {
+	serial, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	y, m, d := sheetsEpoch.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc).AddDate(0, 0, int(serial)), true
+}
+
+// readSheetMileage reads the Date/distance columns (B/C, per the RowMapper
+// column layout) from every tab in mileageTabs, converting the swims tab's
+// meters to miles.
+func readSheetMileage(srv *sheets.Service, spreadsheetID string, loc *time.Location) ([]dateMileage, error) {
+	var entries []dateMileage
+	for _, tab := range mileageTabs {
+		valueRange := fmt.Sprintf("%s!B2:C", tab)
+		resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, valueRange).
+			ValueRenderOption("UNFORMATTED_VALUE").Do()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %s mileage: %w", tab, err)
+		}
+		for i, row := range resp.Values {
+			if len(row) < 2 {
+				continue
+			}
+			sheetRow := i + 2 // row 1 is the header
+			date, ok := parseSheetsDate(row[0], loc)
+			if !ok {
+				log.Warnf("%s row %d: couldn't parse date %v, skipping", tab, sheetRow, row[0])
+				continue
+			}
+			miles, ok := row[1].(float64)
+			if !ok {
+				log.Warnf("%s row %d: couldn't parse mileage %v, skipping", tab, sheetRow, row[1])
+				continue
+			}
+			if tab == "swims" {
+				miles /= 1600 // swimRowMapper writes meters
+			}
+			entries = append(entries, dateMileage{Date: date, Miles: miles})
+		}
+	}
+	return entries, nil
+}
+
+// computeDashboard rolls entries up into 7/30/365-day mileage totals and the
+// current day streak, as of asOf.
+func computeDashboard(entries []dateMileage, asOf time.Time) dashboardStats {
+	var stats dashboardStats
+	activeDays := map[string]bool{}
+	for _, e := range entries {
+		days := int(asOf.Sub(e.Date).Hours() / 24)
+		if days < 0 {
+			continue
+		}
+		if days < 7 {
+			stats.Mileage7Day += e.Miles
+		}
+		if days < 30 {
+			stats.Mileage30Day += e.Miles
+		}
+		if days < 365 {
+			stats.Mileage365Day += e.Miles
+		}
+		activeDays[e.Date.Format("2006-01-02")] = true
+	}
+	stats.StreakDays = currentStreak(activeDays, asOf)
+	return stats
+}
+
+// currentStreak counts consecutive days, walking backwards from asOf, that
+// have at least one activity.
+func currentStreak(activeDays map[string]bool, asOf time.Time) int {
+	streak := 0
+	for day := asOf; activeDays[day.Format("2006-01-02")]; day = day.AddDate(0, 0, -1) {
+		streak++
+	}
+	return streak
+}
+
+// writeDashboard writes stats to dashboardRange.
+func writeDashboard(srv *sheets.Service, spreadsheetID string, stats dashboardStats) error {
+	values := &sheets.ValueRange{
+		MajorDimension: "ROWS",
+		Range:          dashboardRange,
+		Values: [][]interface{}{
+			{"7-day miles", stats.Mileage7Day},
+			{"30-day miles", stats.Mileage30Day},
+			{"365-day miles", stats.Mileage365Day},
+			{"current streak (days)", stats.StreakDays},
+		},
+	}
+	_, err := srv.Spreadsheets.Values.Update(spreadsheetID, dashboardRange, values).ValueInputOption("USER_ENTERED").Do()
+	return err
+}
+
+// refreshDashboard reads the latest mileage from every sport tab and
+// rewrites the dashboard tab's rolling totals and streak.
+func refreshDashboard(srv *sheets.Service, spreadsheetID string, loc *time.Location) (dashboardStats, error) {
+	entries, err := readSheetMileage(srv, spreadsheetID, loc)
+	if err != nil {
+		return dashboardStats{}, err
+	}
+	stats := computeDashboard(entries, time.Now().In(loc))
+	if err := writeDashboard(srv, spreadsheetID, stats); err != nil {
+		return dashboardStats{}, fmt.Errorf("couldn't write dashboard: %w", err)
+	}
+	return stats, nil
+}
+
+// sheetIDForTab looks up the numeric grid ID of a tab by its title, needed
+// because conditional format requests address sheets by ID, not name.
+func sheetIDForTab(srv *sheets.Service, spreadsheetID, tab string) (int64, error) {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetID).Do()
+	if err != nil {
+		return 0, fmt.Errorf("couldn't look up sheet ID for tab %q: %w", tab, err)
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties != nil && sheet.Properties.Title == tab {
+			return sheet.Properties.SheetId, nil
+		}
+	}
+	return 0, fmt.Errorf("no tab named %q in spreadsheet %q", tab, spreadsheetID)
+}
+
+// columnLetter converts a zero-indexed column number to its A1 letter(s).
+func columnLetter(col int) string {
+	letters := ""
+	for col >= 0 {
+		letters = string(rune('A'+col%26)) + letters
+		col = col/26 - 1
+	}
+	return letters
+}
+
+// recencyColors are the background colors colorCodeRecency's three rules
+// paint a cell, in order. A rule on a sheet is recognized as one of ours -
+// and so safe to replace on the next call - by matching one of these.
+var recencyColors = []*sheets.Color{
+	{Red: 0.96, Green: 0.6, Blue: 0.6},
+	{Red: 1, Green: 0.8, Blue: 0.4},
+	{Red: 0.7, Green: 0.9, Blue: 0.7},
+}
+
+func colorsEqual(a, b *sheets.Color) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Red == b.Red && a.Green == b.Green && a.Blue == b.Blue
+}
+
+// isRecencyRule reports whether rule is one colorCodeRecency added on a
+// previous call, identified by its background color matching recencyColors.
+func isRecencyRule(rule *sheets.ConditionalFormatRule) bool {
+	if rule.BooleanRule == nil || rule.BooleanRule.Format == nil {
+		return false
+	}
+	bg := rule.BooleanRule.Format.BackgroundColor
+	for _, c := range recencyColors {
+		if colorsEqual(bg, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// colorCodeRecency paints the date cell at the given zero-indexed row/col on
+// sheetID green if it's within 2 days of today, orange within 5, and red
+// beyond, via live conditional formatting rules. Calling it again for the
+// same sheetID replaces its previous rules rather than adding more, so a
+// sheet that's synced repeatedly doesn't accumulate one set of rules per run.
+func colorCodeRecency(srv *sheets.Service, spreadsheetID string, sheetID int64, row, col int) error {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetID).Do()
+	if err != nil {
+		return fmt.Errorf("couldn't look up existing conditional format rules: %w", err)
+	}
+
+	var requests []*sheets.Request
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties == nil || sheet.Properties.SheetId != sheetID {
+			continue
+		}
+		// Delete back-to-front so each rule's index is still valid at the
+		// point we delete it, even as earlier deletes shift later ones.
+		for i := len(sheet.ConditionalFormats) - 1; i >= 0; i-- {
+			if isRecencyRule(sheet.ConditionalFormats[i]) {
+				requests = append(requests, &sheets.Request{
+					DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{
+						SheetId: sheetID,
+						Index:   int64(i),
+					},
+				})
+			}
+		}
+	}
+
+	cellRange := &sheets.GridRange{
+		SheetId:          sheetID,
+		StartRowIndex:    int64(row),
+		EndRowIndex:      int64(row + 1),
+		StartColumnIndex: int64(col),
+		EndColumnIndex:   int64(col + 1),
+	}
+	cellRef := fmt.Sprintf("%s%d", columnLetter(col), row+1)
+
+	rule := func(formula string, color *sheets.Color) *sheets.Request {
+		return &sheets.Request{
+			AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+				Index: 0,
+				Rule: &sheets.ConditionalFormatRule{
+					Ranges: []*sheets.GridRange{cellRange},
+					BooleanRule: &sheets.BooleanRule{
+						Condition: &sheets.BooleanCondition{
+							Type:   "CUSTOM_FORMULA",
+							Values: []*sheets.ConditionValue{{UserEnteredValue: formula}},
+						},
+						Format: &sheets.CellFormat{BackgroundColor: color},
+					},
+				},
+			},
+		}
+	}
+
+	requests = append(requests,
+		rule(fmt.Sprintf("=TODAY()-%s>5", cellRef), recencyColors[0]),
+		rule(fmt.Sprintf("=AND(TODAY()-%s>2,TODAY()-%s<=5)", cellRef, cellRef), recencyColors[1]),
+		rule(fmt.Sprintf("=TODAY()-%s<=2", cellRef), recencyColors[2]),
+	)
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}).Do()
+	return err
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// minMovingDistanceM is the distance a trackpoint has to move from the
+// previous one to count towards moving time rather than a stopped pause.
+const minMovingDistanceM = 1.0
+
+// earthRadiusM is used by the haversine distance calculation below.
+const earthRadiusM = 6371000.0
+
+// gpxProvider is the ActivityProvider that reads exported GPX/TCX workout
+// files out of a local directory, for athletes who don't want to grant
+// Strava OAuth scopes.
+type gpxProvider struct {
+	path string
+}
+
+func newGPXProvider(path string) *gpxProvider {
+	return &gpxProvider{path: path}
+}
+
+func (p *gpxProvider) Fetch(ctx context.Context, since, until time.Time) ([]Activity, error) {
+	entries, err := os.ReadDir(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read GPX/TCX directory %q: %w", p.path, err)
+	}
+
+	var activities []Activity
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		full := filepath.Join(p.path, entry.Name())
+		var activity Activity
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".gpx":
+			activity, err = parseGPXFile(full)
+		case ".tcx":
+			activity, err = parseTCXFile(full)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse %s: %w", full, err)
+		}
+
+		if activity.StartTime.Before(since) || activity.StartTime.After(until) {
+			continue
+		}
+		activities = append(activities, activity)
+	}
+	return activities, nil
+}
+
+// trackPoint is the common shape both GPX and TCX trackpoints are reduced
+// to before computing distance and moving time.
+type trackPoint struct {
+	Lat  float64
+	Lon  float64
+	Time time.Time
+}
+
+type gpxFile struct {
+	XMLName xml.Name   `xml:"gpx"`
+	Tracks  []gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64   `xml:"lat,attr"`
+	Lon  float64   `xml:"lon,attr"`
+	Time time.Time `xml:"time"`
+}
+
+// parseGPXFile decodes a GPX file into a single Activity, concatenating
+// every segment of every track in file order. GPX carries no sport/ID
+// metadata, so the SportType defaults to "Run" and the activity ID is the
+// file's base name - stable across re-runs so upserts stay idempotent.
+func parseGPXFile(path string) (Activity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Activity{}, err
+	}
+	defer f.Close()
+
+	var doc gpxFile
+	if err := xml.NewDecoder(f).Decode(&doc); err != nil {
+		return Activity{}, fmt.Errorf("couldn't decode GPX: %w", err)
+	}
+
+	var points []trackPoint
+	var name string
+	for _, trk := range doc.Tracks {
+		if name == "" {
+			name = trk.Name
+		}
+		for _, seg := range trk.Segments {
+			for _, pt := range seg.Points {
+				points = append(points, trackPoint{Lat: pt.Lat, Lon: pt.Lon, Time: pt.Time})
+			}
+		}
+	}
+
+	activity := activityFromTrack(name, "Run", points)
+	activity.ID = filepath.Base(path)
+	return activity, nil
+}
+
+type tcxFile struct {
+	XMLName    xml.Name      `xml:"TrainingCenterDatabase"`
+	Activities []tcxActivity `xml:"Activities>Activity"`
+}
+
+type tcxActivity struct {
+	Sport string   `xml:"Sport,attr"`
+	Laps  []tcxLap `xml:"Lap"`
+}
+
+type tcxLap struct {
+	Tracks []tcxTrack `xml:"Track"`
+}
+
+type tcxTrack struct {
+	Points []tcxPoint `xml:"Trackpoint"`
+}
+
+type tcxPoint struct {
+	Time time.Time `xml:"Time"`
+	Lat  float64   `xml:"Position>LatitudeDegrees"`
+	Lon  float64   `xml:"Position>LongitudeDegrees"`
+}
+
+// tcxSportType maps Garmin's TCX Sport attribute onto our SportType values.
+var tcxSportType = map[string]string{
+	"Running": "Run",
+	"Biking":  "Ride",
+}
+
+// parseTCXFile decodes a TCX file's first activity into an Activity,
+// concatenating every lap's track in file order. The activity ID is the
+// file's base name, for the same idempotency reason as parseGPXFile.
+func parseTCXFile(path string) (Activity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Activity{}, err
+	}
+	defer f.Close()
+
+	var doc tcxFile
+	if err := xml.NewDecoder(f).Decode(&doc); err != nil {
+		return Activity{}, fmt.Errorf("couldn't decode TCX: %w", err)
+	}
+	if len(doc.Activities) == 0 {
+		return Activity{}, fmt.Errorf("no activities in TCX file")
+	}
+
+	tcxAct := doc.Activities[0]
+	sport, ok := tcxSportType[tcxAct.Sport]
+	if !ok {
+		sport = "Run"
+	}
+
+	var points []trackPoint
+	for _, lap := range tcxAct.Laps {
+		for _, trk := range lap.Tracks {
+			for _, pt := range trk.Points {
+				points = append(points, trackPoint{Lat: pt.Lat, Lon: pt.Lon, Time: pt.Time})
+			}
+		}
+	}
+
+	activity := activityFromTrack("", sport, points)
+	activity.ID = filepath.Base(path)
+	return activity, nil
+}
+
+// activityFromTrack builds an Activity from a list of trackpoints, computing
+// total distance as the haversine sum between consecutive points and moving
+// time as the sum of deltas between points that actually moved - stationary
+// gaps (GPS drift aside) are treated as paused and excluded.
+func activityFromTrack(name, sportType string, points []trackPoint) Activity {
+	if len(points) == 0 {
+		return Activity{Name: name, SportType: sportType}
+	}
+
+	var distance float64
+	var movingTime time.Duration
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1], points[i]
+		d := haversineMeters(prev.Lat, prev.Lon, cur.Lat, cur.Lon)
+		distance += d
+		if d >= minMovingDistanceM {
+			movingTime += cur.Time.Sub(prev.Time)
+		}
+	}
+
+	return Activity{
+		Name:        name,
+		SportType:   sportType,
+		StartTime:   points[0].Time,
+		DistanceM:   distance,
+		MovingTime:  movingTime,
+		ElapsedTime: points[len(points)-1].Time.Sub(points[0].Time),
+	}
+}
+
+// haversineMeters returns the great-circle distance between two lat/lon
+// points, in meters.
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadiusM * c
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/sheets/v4"
+)
+
+// RowMapper knows how to turn an Activity of a particular SportType into a
+// sheet row, and which tab that row belongs on. Column A of every tab is the
+// activity ID, which upsertActivities uses to decide between an update and
+// an append.
+type RowMapper interface {
+	Tab() string
+	Header() []interface{}
+	Row(activity Activity) []interface{}
+}
+
+type runRowMapper struct{}
+
+func (runRowMapper) Tab() string { return "runs" }
+
+func (runRowMapper) Header() []interface{} {
+	return []interface{}{"Activity ID", "Date", "Miles", "Duration", "Pace"}
+}
+
+func (runRowMapper) Row(a Activity) []interface{} {
+	mileage := a.DistanceM / 1600
+	return []interface{}{
+		a.ID,
+		a.StartTime.Format("2006-01-02"),
+		mileage,
+		a.MovingTime.String(),
+		calculateAvgPace(a.MovingTime.Seconds(), mileage),
+	}
+}
+
+type rideRowMapper struct{}
+
+func (rideRowMapper) Tab() string { return "rides" }
+
+func (rideRowMapper) Header() []interface{} {
+	return []interface{}{"Activity ID", "Date", "Miles", "Duration", "Avg MPH", "Elevation Gain (ft)"}
+}
+
+func (rideRowMapper) Row(a Activity) []interface{} {
+	mileage := a.DistanceM / 1600
+	return []interface{}{
+		a.ID,
+		a.StartTime.Format("2006-01-02"),
+		mileage,
+		a.MovingTime.String(),
+		a.AverageSpeed * 2.23694,
+		a.TotalElevationGain * 3.28084,
+	}
+}
+
+type swimRowMapper struct{}
+
+func (swimRowMapper) Tab() string { return "swims" }
+
+func (swimRowMapper) Header() []interface{} {
+	return []interface{}{"Activity ID", "Date", "Meters", "Duration", "Pace / 100m"}
+}
+
+func (swimRowMapper) Row(a Activity) []interface{} {
+	pace := "n/a"
+	if a.DistanceM > 0 {
+		pace = calculateAvgPace(a.MovingTime.Seconds(), a.DistanceM/100)
+	}
+	return []interface{}{
+		a.ID,
+		a.StartTime.Format("2006-01-02"),
+		a.DistanceM,
+		a.MovingTime.String(),
+		pace,
+	}
+}
+
+type weightTrainingRowMapper struct{}
+
+func (weightTrainingRowMapper) Tab() string { return "weight_training" }
+
+func (weightTrainingRowMapper) Header() []interface{} {
+	return []interface{}{"Activity ID", "Date", "Duration", "Name"}
+}
+
+func (weightTrainingRowMapper) Row(a Activity) []interface{} {
+	return []interface{}{
+		a.ID,
+		a.StartTime.Format("2006-01-02"),
+		a.MovingTime.String(),
+		a.Name,
+	}
+}
+
+// rowMappers holds the RowMapper for every SportType we know how to write to
+// the sheet. Activities with an unrecognized SportType are skipped with a
+// warning rather than written with garbage columns.
+var rowMappers = map[string]RowMapper{
+	"Run":            runRowMapper{},
+	"Ride":           rideRowMapper{},
+	"Swim":           swimRowMapper{},
+	"WeightTraining": weightTrainingRowMapper{},
+}
+
+// upsertActivities routes activities to their sport's tab, seeding the tab's
+// header row from its RowMapper if it's missing one, reads the existing
+// activity-ID column on each tab to find which rows already exist, and
+// writes every sport's changes in a single Values.BatchUpdate call: existing
+// activity IDs get their row overwritten in place, new ones get appended
+// after the last known row. It returns, for each tab it wrote to, the row
+// number holding the most recent (by StartTime) activity that was just
+// synced, so callers know which cell to run recency formatting over.
+func upsertActivities(srv *sheets.Service, spreadsheetID string, activities []Activity) (map[string]int, error) {
+	bySport := map[string][]Activity{}
+	for _, a := range activities {
+		bySport[a.SportType] = append(bySport[a.SportType], a)
+	}
+
+	var data []*sheets.ValueRange
+	mostRecentRow := map[string]int{}
+	for sport, sportActivities := range bySport {
+		mapper, ok := rowMappers[sport]
+		if !ok {
+			log.Warnf("no RowMapper for sport type %q, skipping %d activities", sport, len(sportActivities))
+			continue
+		}
+
+		headerRange := fmt.Sprintf("%s!A1:A1", mapper.Tab())
+		headerResp, err := srv.Spreadsheets.Values.Get(spreadsheetID, headerRange).Do()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't check %s header row: %w", mapper.Tab(), err)
+		}
+		if len(headerResp.Values) == 0 {
+			data = append(data, &sheets.ValueRange{
+				Range:  fmt.Sprintf("%s!A1", mapper.Tab()),
+				Values: [][]interface{}{mapper.Header()},
+			})
+		}
+
+		idRange := fmt.Sprintf("%s!A2:A", mapper.Tab())
+		resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, idRange).Do()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read existing %s activity IDs: %w", mapper.Tab(), err)
+		}
+
+		existingRow := map[string]int{}
+		for i, row := range resp.Values {
+			if len(row) == 0 {
+				continue
+			}
+			existingRow[fmt.Sprintf("%v", row[0])] = i + 2 // row 1 is the header
+		}
+		nextRow := len(resp.Values) + 2
+
+		var mostRecent Activity
+		for _, a := range sportActivities {
+			row, ok := existingRow[a.ID]
+			if !ok {
+				row = nextRow
+				nextRow++
+			}
+			data = append(data, &sheets.ValueRange{
+				Range:  fmt.Sprintf("%s!A%d", mapper.Tab(), row),
+				Values: [][]interface{}{mapper.Row(a)},
+			})
+			if a.StartTime.After(mostRecent.StartTime) {
+				mostRecent = a
+				mostRecentRow[mapper.Tab()] = row
+			}
+		}
+	}
+
+	if len(data) == 0 {
+		return mostRecentRow, nil
+	}
+
+	_, err := srv.Spreadsheets.Values.BatchUpdate(spreadsheetID, &sheets.BatchUpdateValuesRequest{
+		ValueInputOption: "USER_ENTERED",
+		Data:             data,
+	}).Do()
+	if err != nil {
+		return nil, err
+	}
+	return mostRecentRow, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// stravaActivitiesURL is the documented SummaryActivity list endpoint for the
+// authenticated athlete. https://developers.strava.com/docs/reference/#api-Activities-getLoggedInAthleteActivities
+const stravaActivitiesURL = "https://www.strava.com/api/v3/athlete/activities"
+
+// activitiesPerPage is the largest page size Strava allows.
+const activitiesPerPage = 200
+
+// rateLimitSleepThreshold is the fraction of the short-term (15 minute) quota
+// at which we back off rather than risk a 429.
+const rateLimitSleepThreshold = 0.9
+
+// rateLimitSleepWindow matches Strava's short-term rate limit window.
+const rateLimitSleepWindow = 15 * time.Minute
+
+// stravaActivity holds all relevant data for a particular strava activity.
+// Fields follow Strava's documented SummaryActivity shape.
+type stravaActivity struct {
+	ID                 int64     `json:"id"`
+	Name               string    `json:"name"`
+	Type               string    `json:"type"`
+	SportType          string    `json:"sport_type"`
+	StartTime          string    `json:"start_date_local"`
+	DistanceM          float64   `json:"distance"`
+	MovingTimeSec      int       `json:"moving_time"`
+	ElapsedTimeSec     int       `json:"elapsed_time"`
+	TotalElevationGain float64   `json:"total_elevation_gain"`
+	AverageSpeed       float64   `json:"average_speed"`
+	AverageHeartrate   float64   `json:"average_heartrate"`
+	MaxHeartrate       float64   `json:"max_heartrate"`
+	KudosCount         int       `json:"kudos_count"`
+	Trainer            bool      `json:"trainer"`
+	Commute            bool      `json:"commute"`
+	Map                stravaMap `json:"map"`
+}
+
+// stravaMap is the polyline summary Strava attaches to each activity.
+type stravaMap struct {
+	SummaryPolyline string `json:"summary_polyline"`
+}
+
+type stravaResponse []stravaActivity
+
+func getDuration(timeSec int) time.Duration {
+	timeStr := fmt.Sprintf("%ds", timeSec)
+	duration, err := time.ParseDuration(timeStr)
+	if err != nil {
+		log.Print(err)
+	}
+	return duration
+}
+
+// toActivity maps a stravaActivity onto the canonical Activity shape that
+// the rest of the tool works with.
+func (a stravaActivity) toActivity() Activity {
+	loc, _ := time.LoadLocation("America/New_York")
+	startTime, err := time.ParseInLocation(time.RFC3339, a.StartTime, loc)
+	if err != nil {
+		log.Warnf("couldn't parse Strava start time %q for activity %d: %v", a.StartTime, a.ID, err)
+	}
+	return Activity{
+		ID:                 strconv.FormatInt(a.ID, 10),
+		Name:               a.Name,
+		SportType:          a.SportType,
+		StartTime:          startTime,
+		DistanceM:          a.DistanceM,
+		MovingTime:         getDuration(a.MovingTimeSec),
+		ElapsedTime:        time.Duration(a.ElapsedTimeSec) * time.Second,
+		TotalElevationGain: a.TotalElevationGain,
+		AverageSpeed:       a.AverageSpeed,
+	}
+}
+
+// stravaProvider is the ActivityProvider backed by the Strava API.
+type stravaProvider struct {
+	client *http.Client
+}
+
+// newStravaProvider builds a stravaProvider for one task's Strava credentials.
+// It requires tokenFile to already hold a token cached by a prior
+// -authorize-strava run; it never launches the interactive OAuth flow
+// itself, since it's called from the unattended sync path.
+func newStravaProvider(ctx context.Context, clientID, clientSecret, tokenFile string) (*stravaProvider, error) {
+	conf, err := stravaConfig(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if tokenFile == "" {
+		tokenFile = defaultStravaTokenFile
+	}
+	client, err := getStravaClient(ctx, conf, tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	return &stravaProvider{client: client}, nil
+}
+
+func (p *stravaProvider) Fetch(ctx context.Context, since, until time.Time) ([]Activity, error) {
+	raw, err := fetchActivities(ctx, p.client, since, until)
+	if err != nil {
+		return nil, err
+	}
+	activities := make([]Activity, 0, len(*raw))
+	for _, a := range *raw {
+		activities = append(activities, a.toActivity())
+	}
+	return activities, nil
+}
+
+// fetchActivities pages through /athlete/activities between since and until,
+// requesting activitiesPerPage activities at a time until an empty page comes
+// back. It backs off when the short-term rate limit is close to exhausted.
+func fetchActivities(ctx context.Context, client *http.Client, since, until time.Time) (*stravaResponse, error) {
+	var all stravaResponse
+	for page := 1; ; page++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", stravaActivitiesURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create Strava GET request: %w", err)
+		}
+		q := req.URL.Query()
+		q.Set("after", strconv.FormatInt(since.Unix(), 10))
+		q.Set("before", strconv.FormatInt(until.Unix(), 10))
+		q.Set("per_page", strconv.Itoa(activitiesPerPage))
+		q.Set("page", strconv.Itoa(page))
+		req.URL.RawQuery = q.Encode()
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't fetch Strava activities: %w", err)
+		}
+
+		var pageActivities stravaResponse
+		err = json.NewDecoder(resp.Body).Decode(&pageActivities)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode the Strava response: %w", err)
+		}
+
+		sleepIfRateLimited(resp.Header)
+
+		if len(pageActivities) == 0 {
+			break
+		}
+		all = append(all, pageActivities...)
+	}
+	return &all, nil
+}
+
+// sleepIfRateLimited reads Strava's X-RateLimit-Usage/X-RateLimit-Limit
+// headers (each "short,daily") and sleeps out the short-term window if we're
+// close to tripping it.
+func sleepIfRateLimited(header http.Header) {
+	usage := header.Get("X-RateLimit-Usage")
+	limit := header.Get("X-RateLimit-Limit")
+	if usage == "" || limit == "" {
+		return
+	}
+	usageShort, _, err := parseRateLimitPair(usage)
+	if err != nil {
+		log.Debugf("couldn't parse X-RateLimit-Usage %q: %v", usage, err)
+		return
+	}
+	limitShort, _, err := parseRateLimitPair(limit)
+	if err != nil || limitShort == 0 {
+		log.Debugf("couldn't parse X-RateLimit-Limit %q: %v", limit, err)
+		return
+	}
+	if float64(usageShort)/float64(limitShort) >= rateLimitSleepThreshold {
+		log.Warnf("near Strava's short-term rate limit (%d/%d used), sleeping %v", usageShort, limitShort, rateLimitSleepWindow)
+		time.Sleep(rateLimitSleepWindow)
+	}
+}
+
+// parseRateLimitPair splits a "short,daily" rate limit header value.
+func parseRateLimitPair(v string) (short int, daily int, err error) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rate limit header value %q", v)
+	}
+	short, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	daily, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, err
+	}
+	return short, daily, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// defaultStravaTokenFile is where a task's refreshed Strava token tuple is
+// cached between runs if it doesn't set strava_token_file in config.yaml.
+const defaultStravaTokenFile = "strava_token.json"
+
+// stravaEndpoint is Strava's OAuth2 token/authorize endpoint pair.
+// https://developers.strava.com/docs/authentication/
+var stravaEndpoint = oauth2.Endpoint{
+	AuthURL:  "https://www.strava.com/oauth/authorize",
+	TokenURL: "https://www.strava.com/oauth/token",
+}
+
+// stravaConfig builds an oauth2.Config for Strava from a task's client ID
+// and secret, mirroring how the Sheets config is built from the downloaded
+// credentials file.
+func stravaConfig(clientID, clientSecret string) (*oauth2.Config, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("strava_client_id and strava_client_secret must both be set")
+	}
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     stravaEndpoint,
+		Scopes:       []string{"activity:read_all"},
+	}, nil
+}
+
+// stravaTokenFromFile reads a cached {access_token, refresh_token, expiry}
+// tuple from path, the same shape tokenFromFile reads for the Sheets token.
+func stravaTokenFromFile(path string) (*oauth2.Token, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tok := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(tok)
+	return tok, err
+}
+
+// saveStravaToken persists a refreshed Strava token to path so the next run
+// doesn't need to re-authorize from scratch.
+func saveStravaToken(path string, token *oauth2.Token) error {
+	log.Debugf("saving Strava token to %v", path)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to cache Strava token: %w", err)
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(token)
+}
+
+// getTokenFromStravaWeb walks the user through the first-run authorization
+// flow, the same way getTokenFromWeb does for the Sheets config. It's only
+// ever invoked from the interactive -authorize-strava entrypoint, never from
+// an unattended runSyncTask - blocking on stdin there would hang a cron run.
+func getTokenFromStravaWeb(config *oauth2.Config) (*oauth2.Token, error) {
+	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	fmt.Printf("Go to the following link and type the authorization code: \n%v\n", authURL)
+	var authCode string
+	if _, err := fmt.Scan(&authCode); err != nil {
+		return nil, fmt.Errorf("unable to read authorization code: %w", err)
+	}
+	tok, err := config.Exchange(context.TODO(), authCode)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve token from Strava: %w", err)
+	}
+	return tok, nil
+}
+
+// authorizeStrava runs the interactive first-run authorization flow for one
+// task and caches the resulting token at tokenFile. It's meant to be run by
+// hand, once per task, before that task's first unattended sync.
+func authorizeStrava(config *oauth2.Config, tokenFile string) error {
+	tok, err := getTokenFromStravaWeb(config)
+	if err != nil {
+		return err
+	}
+	return saveStravaToken(tokenFile, tok)
+}
+
+// persistingTokenSource wraps an oauth2.TokenSource and writes the token back
+// to disk whenever it changes, so a refresh is never lost between runs. A
+// failure to persist is logged but doesn't fail the Token() call - the
+// caller still gets a good, usable token even if the write to disk failed.
+type persistingTokenSource struct {
+	path   string
+	source oauth2.TokenSource
+	last   string
+}
+
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := p.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok.AccessToken != p.last {
+		if err := saveStravaToken(p.path, tok); err != nil {
+			log.Warnf("couldn't persist refreshed Strava token to %s: %v", p.path, err)
+		}
+		p.last = tok.AccessToken
+	}
+	return tok, nil
+}
+
+// getStravaClient returns an *http.Client backed by an oauth2.TokenSource
+// that transparently exchanges the refresh token for a new access token
+// against https://www.strava.com/oauth/token whenever the cached one has
+// expired, persisting the result back to tokenFile. It requires tokenFile to
+// already hold a token cached by a prior authorizeStrava run - an unattended
+// sync must never fall back to the interactive web flow.
+func getStravaClient(ctx context.Context, config *oauth2.Config, tokenFile string) (*http.Client, error) {
+	tok, err := stravaTokenFromFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("no cached Strava token at %q; run with -authorize-strava first: %w", tokenFile, err)
+	}
+	source := &persistingTokenSource{
+		path:   tokenFile,
+		source: config.TokenSource(ctx, tok),
+		last:   tok.AccessToken,
+	}
+	return oauth2.NewClient(ctx, source), nil
+}